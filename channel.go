@@ -1,10 +1,12 @@
 package rpcpool
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/rpc"
 	"sync"
+	"time"
 )
 
 // channelPool implements the Pool interface based on buffered channels.
@@ -14,14 +16,56 @@ type channelPool struct {
 	conns chan *rpc.Client
 	// used connections
 	workConns chan *rpc.Client
+	// sem bounds the number of connections checked out via Get to maxCap,
+	// letting GetContext block on it instead of busy-looping. Idle
+	// connections hold no slot.
+	sem chan struct{}
+	// closed is closed once Close() runs, signaling the reaper and health
+	// checker to stop.
+	closed chan struct{}
+	// closeMu serializes Close() against the reaper and health checker, so
+	// neither can be mid-way through sending to conns when Close() closes
+	// it out from under them.
+	closeMu sync.RWMutex
 
 	// net.Conn generator
-	factory Factory
+	factory FactoryWithContext
+	// ping, if set, validates a connection pulled from the idle pool before
+	// Get hands it to a caller. It is set once at construction time.
+	ping Ping
+	// mode selects whether GetContext blocks or fails fast once maxCap
+	// connections are in use. Zero value is ModeBlocking.
+	mode Mode
+
+	// metaMu guards meta, which tracks per-connection lifecycle timestamps
+	// used by the idle and max-lifetime eviction in options.go.
+	metaMu              sync.Mutex
+	meta                map[*rpc.Client]*connMeta
+	idleTimeout         time.Duration
+	maxLifetime         time.Duration
+	healthCheckInterval time.Duration
+	minCap              int
+
+	// stats backs Stats(); hooks are optional lifecycle callbacks set once
+	// at construction time.
+	stats stats
+	hooks Hooks
 }
 
 // Factory is a function to create new connections.
 type Factory func() (*rpc.Client, error)
 
+// FactoryWithContext is a function to create new connections that honors
+// caller-supplied cancellation and deadlines, mirroring the context-aware
+// dialer hooks found in grpc-pool style designs.
+type FactoryWithContext func(ctx context.Context) (*rpc.Client, error)
+
+// Ping is an optional health check invoked on a connection pulled from the
+// idle pool before it is handed to a caller. It should return a non-nil
+// error if conn is no longer usable, e.g. because the server restarted and
+// net/rpc's Call would return rpc.ErrShutdown on first use.
+type Ping func(conn *rpc.Client) error
+
 // NewChannelPool returns a new pool based on buffered channels with an initial
 // capacity and maximum capacity. Factory is used when initial capacity is
 // greater than zero to fill the pool. A zero initialCap doesn't fill the Pool
@@ -29,6 +73,16 @@ type Factory func() (*rpc.Client, error)
 // available in the pool, a new connection will be created via the Factory()
 // method.
 func NewChannelPool(initialCap, maxCap int, factory Factory) (Pool, error) {
+	return NewChannelPoolWithContext(context.Background(), initialCap, maxCap,
+		func(ctx context.Context) (*rpc.Client, error) {
+			return factory()
+		})
+}
+
+// NewChannelPoolWithContext is like NewChannelPool but takes a
+// FactoryWithContext, used both to fill the initial capacity (with ctx) and
+// later by GetContext to dial replacement connections.
+func NewChannelPoolWithContext(ctx context.Context, initialCap, maxCap int, factory FactoryWithContext) (Pool, error) {
 	if initialCap < 0 || maxCap <= 0 || initialCap > maxCap {
 		return nil, errors.New("invalid capacity settings")
 	}
@@ -36,24 +90,28 @@ func NewChannelPool(initialCap, maxCap int, factory Factory) (Pool, error) {
 	c := &channelPool{
 		conns:     make(chan *rpc.Client, maxCap),
 		workConns: make(chan *rpc.Client, maxCap),
+		sem:       make(chan struct{}, maxCap),
+		closed:    make(chan struct{}),
 		factory:   factory,
+		meta:      make(map[*rpc.Client]*connMeta),
 	}
 
 	// create initial connections, if something goes wrong,
 	// just close the pool error out.
 	for i := 0; i < initialCap; i++ {
-		conn, err := factory()
+		conn, err := factory(ctx)
 		if err != nil {
 			c.Close()
 			return nil, fmt.Errorf("factory is not able to fill the pool: %s", err)
 		}
+		c.recordNew(conn)
 		c.conns <- conn
 	}
 
 	return c, nil
 }
 
-func (c *channelPool) getConnsAndFactory() (chan *rpc.Client, Factory) {
+func (c *channelPool) getConnsAndFactory() (chan *rpc.Client, FactoryWithContext) {
 	c.mu.Lock()
 	conns := c.conns
 	factory := c.factory
@@ -61,38 +119,90 @@ func (c *channelPool) getConnsAndFactory() (chan *rpc.Client, Factory) {
 	return conns, factory
 }
 
-// Get implements the Pool interfaces Get() method. If there is no new
-// connection available in the pool, a new connection will be created via the
-// Factory() method.
+// Get implements the Pool interfaces Get() method. It is GetContext called
+// with context.Background(), i.e. in ModeBlocking it waits indefinitely for a
+// connection; in ModeNonBlocking it fails with ErrPoolExhausted at maxCap.
 func (c *channelPool) Get() (*rpc.Client, error) {
+	return c.GetContext(context.Background())
+}
+
+// GetContext implements the Pool interfaces GetContext() method. If there is
+// no idle connection available in the pool, a new connection is created via
+// the Factory() method as long as doing so stays within maxCap. Once maxCap
+// connections are in use, GetContext either blocks until one is returned via
+// PutBack or ctx is done (ModeBlocking, returning ErrTimeout in the latter
+// case), or fails immediately with ErrPoolExhausted (ModeNonBlocking). Idle
+// connections that have expired or fail the optional Ping check are
+// discarded and replaced rather than handed to the caller.
+func (c *channelPool) GetContext(ctx context.Context) (*rpc.Client, error) {
 	conns, factory := c.getConnsAndFactory()
 	if conns == nil {
 		return nil, ErrClosed
 	}
 
-	// wrap our connections with out custom net.Conn implementation (wrapConn
-	// method) that puts the connection back to the pool if it's closed.
+	waitStart := time.Now()
+	if c.mode == ModeNonBlocking {
+		select {
+		case c.sem <- struct{}{}:
+		default:
+			return nil, ErrPoolExhausted
+		}
+	} else {
+		select {
+		case c.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ErrTimeout
+		}
+	}
+	c.stats.recordWait(time.Since(waitStart))
+
 	for {
 		select {
-		case conn := <-conns:
-			c.workConns <- conn
-			if conn == nil {
+		case conn, ok := <-conns:
+			if !ok || conn == nil {
+				<-c.sem
 				return nil, ErrClosed
 			}
-			return conn, nil
-		default:
-			if len(c.workConns) >= cap(c.workConns) {
+			if c.idleExpired(conn) || !c.checkPing(conn) {
+				c.forget(conn)
+				c.closeConn(conn)
 				continue
 			}
-			conn, err := factory()
+			c.workConns <- conn
+			c.runHook(c.hooks.OnGet, conn)
+			return conn, nil
+		default:
+			conn, err := factory(ctx)
 			if err != nil {
+				<-c.sem
 				return nil, err
 			}
+			c.recordNew(conn)
 			c.workConns <- conn
+			c.runHook(c.hooks.OnGet, conn)
 			return conn, nil
 		}
 	}
+}
 
+// closeConn closes conn for good, updating TotalClosed and firing OnClose.
+func (c *channelPool) closeConn(conn *rpc.Client) error {
+	c.stats.recordClose()
+	c.runHook(c.hooks.OnClose, conn)
+	return conn.Close()
+}
+
+// checkPing reports whether conn passes the pool's Ping hook, if any. A pool
+// with no Ping configured treats every connection as healthy.
+func (c *channelPool) checkPing(conn *rpc.Client) bool {
+	if c.ping == nil {
+		return true
+	}
+	if err := c.ping(conn); err != nil {
+		c.markUnhealthy(conn)
+		return false
+	}
+	return true
 }
 
 // put puts the connection back to the pool. If the pool is full or closed,
@@ -101,29 +211,68 @@ func (c *channelPool) PutBack(conn *rpc.Client) error {
 	if conn == nil {
 		return errors.New("connection is nil. rejecting")
 	}
+	c.runHook(c.hooks.OnPutBack, conn)
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if c.conns == nil {
 		// pool is closed, close passed connection
-		return conn.Close()
+		<-c.workConns
+		c.release()
+		c.forget(conn)
+		return c.closeConn(conn)
+	}
+
+	if c.lifetimeExpired(conn) || c.isUnhealthy(conn) {
+		<-c.workConns
+		c.release()
+		c.forget(conn)
+		return c.closeConn(conn)
 	}
+	c.touch(conn)
 
 	// put the resource back into the pool. If the pool is full, this will
 	// block and the default case will be executed.
 	select {
 	case c.conns <- conn:
 		<-c.workConns
+		c.release()
 		return nil
 	default:
 		// pool is full, close passed connection
 		<-c.workConns
-		return conn.Close()
+		c.release()
+		c.forget(conn)
+		return c.closeConn(conn)
+	}
+}
+
+// PutBackWithError is like PutBack, but additionally lets the caller report
+// that it encountered an RPC error using conn, marking it unhealthy so
+// PutBack closes it instead of returning it to the pool for reuse. A nil
+// rpcErr behaves exactly like PutBack.
+func (c *channelPool) PutBackWithError(conn *rpc.Client, rpcErr error) error {
+	if conn != nil && rpcErr != nil {
+		c.markUnhealthy(conn)
+	}
+	return c.PutBack(conn)
+}
+
+// release frees the sem slot that GetContext acquired for this connection.
+func (c *channelPool) release() {
+	select {
+	case <-c.sem:
+	default:
 	}
 }
 
 func (c *channelPool) Close() {
+	// Block until any in-flight reapIdle/healthCheckIdle finishes, so
+	// neither can send to conns/workConns after this closes them.
+	c.closeMu.Lock()
+	defer c.closeMu.Unlock()
+
 	c.mu.Lock()
 	conns := c.conns
 	workConns := c.workConns
@@ -135,16 +284,28 @@ func (c *channelPool) Close() {
 		return
 	}
 
+	select {
+	case <-c.closed:
+	default:
+		close(c.closed)
+	}
+
 	close(conns)
 	for conn := range conns {
-		conn.Close()
+		if conn != nil {
+			c.forget(conn)
+			c.closeConn(conn)
+		}
 	}
 	if workConns == nil {
 		return
 	}
 	close(workConns)
 	for conn := range workConns {
-		conn.Close()
+		if conn != nil {
+			c.forget(conn)
+			c.closeConn(conn)
+		}
 	}
 }
 
@@ -152,3 +313,69 @@ func (c *channelPool) Len() int {
 	conns, _ := c.getConnsAndFactory()
 	return len(conns)
 }
+
+// inUse returns the number of connections currently checked out of the
+// pool, used by MultiEndpointPool's least-in-use balancing policy.
+func (c *channelPool) inUse() int {
+	c.mu.Lock()
+	workConns := c.workConns
+	c.mu.Unlock()
+	return len(workConns)
+}
+
+// SetMaxCap implements the Pool interfaces SetMaxCap() method. It replaces
+// the underlying channels with ones sized for n, carrying over any idle
+// connections. It refuses to run while connections are checked out, since
+// there is no safe way to resize workConns and sem out from under a
+// concurrent Get/PutBack.
+func (c *channelPool) SetMaxCap(n int) error {
+	if n <= 0 {
+		return errors.New("rpcpool: maxCap must be positive")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conns == nil {
+		return ErrClosed
+	}
+	if len(c.workConns) > 0 {
+		return fmt.Errorf("rpcpool: cannot resize while %d connections are checked out", len(c.workConns))
+	}
+	if n < len(c.conns) {
+		return fmt.Errorf("rpcpool: maxCap %d is below current idle count %d", n, len(c.conns))
+	}
+
+	newConns := make(chan *rpc.Client, n)
+	for i, ln := 0, len(c.conns); i < ln; i++ {
+		newConns <- <-c.conns
+	}
+	c.conns = newConns
+	c.workConns = make(chan *rpc.Client, n)
+	c.sem = make(chan struct{}, n)
+	return nil
+}
+
+// SetMinCap implements the Pool interfaces SetMinCap() method. It dials
+// connections via the Factory until the idle pool holds at least n, up to
+// its current capacity.
+func (c *channelPool) SetMinCap(n int) error {
+	if n < 0 {
+		return errors.New("rpcpool: minCap must not be negative")
+	}
+
+	conns, factory := c.getConnsAndFactory()
+	if conns == nil {
+		return ErrClosed
+	}
+
+	for len(conns) < n && len(conns) < cap(conns) {
+		conn, err := factory(context.Background())
+		if err != nil {
+			return fmt.Errorf("rpcpool: factory is not able to grow the pool: %s", err)
+		}
+		c.recordNew(conn)
+		conns <- conn
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package rpcpool
+
+import (
+	"context"
+	"net"
+	"net/rpc"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// echoService is a minimal net/rpc service used to produce real *rpc.Client
+// connections for tests, without a network listener.
+type echoService struct{}
+
+func (echoService) Echo(args *int, reply *int) error {
+	*reply = *args
+	return nil
+}
+
+// newTestClientFactory returns a Factory that dials a real *rpc.Client over
+// an in-memory net.Pipe, backed by a shared Echo service.
+func newTestClientFactory(t *testing.T) Factory {
+	t.Helper()
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("Echo", echoService{}); err != nil {
+		t.Fatalf("register service: %v", err)
+	}
+	return func() (*rpc.Client, error) {
+		serverConn, clientConn := net.Pipe()
+		go srv.ServeConn(serverConn)
+		return rpc.NewClient(clientConn), nil
+	}
+}
+
+func TestGetContextBlocksUntilPutBack(t *testing.T) {
+	p, err := NewChannelPool(1, 1, newTestClientFactory(t))
+	if err != nil {
+		t.Fatalf("NewChannelPool: %v", err)
+	}
+	defer p.Close()
+
+	conn, err := p.Get()
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := p.GetContext(ctx); err != ErrTimeout {
+		t.Fatalf("expected ErrTimeout while at maxCap, got %v", err)
+	}
+
+	if err := p.PutBack(conn); err != nil {
+		t.Fatalf("PutBack: %v", err)
+	}
+
+	ctx2, cancel2 := context.WithTimeout(context.Background(), time.Second)
+	defer cancel2()
+	if _, err := p.GetContext(ctx2); err != nil {
+		t.Fatalf("expected a connection to be available after PutBack, got %v", err)
+	}
+}
+
+func TestGetContextBoundsConcurrency(t *testing.T) {
+	const maxCap = 3
+	p, err := NewChannelPool(0, maxCap, newTestClientFactory(t))
+	if err != nil {
+		t.Fatalf("NewChannelPool: %v", err)
+	}
+	defer p.Close()
+
+	var inUse, maxSeen int32
+	var wg sync.WaitGroup
+	for i := 0; i < maxCap*4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := p.Get()
+			if err != nil {
+				t.Errorf("Get: %v", err)
+				return
+			}
+			n := atomic.AddInt32(&inUse, 1)
+			for {
+				old := atomic.LoadInt32(&maxSeen)
+				if n <= old || atomic.CompareAndSwapInt32(&maxSeen, old, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&inUse, -1)
+			if err := p.PutBack(conn); err != nil {
+				t.Errorf("PutBack: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxSeen > maxCap {
+		t.Fatalf("sem allowed %d concurrent connections, want at most %d", maxSeen, maxCap)
+	}
+}
@@ -0,0 +1,27 @@
+package rpcpool
+
+import "testing"
+
+func TestModeNonBlockingReturnsErrPoolExhausted(t *testing.T) {
+	p, err := NewChannelPoolWithOptions(1, 1, newTestClientFactory(t), Options{Mode: ModeNonBlocking})
+	if err != nil {
+		t.Fatalf("NewChannelPoolWithOptions: %v", err)
+	}
+	defer p.Close()
+
+	conn, err := p.Get()
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	if _, err := p.Get(); err != ErrPoolExhausted {
+		t.Fatalf("expected ErrPoolExhausted at maxCap in ModeNonBlocking, got %v", err)
+	}
+
+	if err := p.PutBack(conn); err != nil {
+		t.Fatalf("PutBack: %v", err)
+	}
+	if _, err := p.Get(); err != nil {
+		t.Fatalf("expected a connection to be available after PutBack, got %v", err)
+	}
+}
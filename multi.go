@@ -0,0 +1,300 @@
+package rpcpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/rpc"
+	"sync"
+	"time"
+)
+
+// ErrNoHealthyEndpoints is returned by MultiEndpointPool's Get/GetContext
+// when every endpoint is currently marked unhealthy.
+var ErrNoHealthyEndpoints = errors.New("rpcpool: no healthy endpoints available")
+
+var _ Pool = (*MultiEndpointPool)(nil)
+
+// Dialer creates a connection to a specific RPC server address, for use with
+// NewMultiEndpointPool.
+type Dialer func(addr string) (*rpc.Client, error)
+
+// Balance selects how MultiEndpointPool picks among healthy endpoints.
+type Balance int
+
+const (
+	// BalanceRoundRobin cycles through healthy endpoints in turn. It is the
+	// zero value and the default balance policy.
+	BalanceRoundRobin Balance = iota
+	// BalanceLeastInUse picks the healthy endpoint with the fewest
+	// connections currently checked out.
+	BalanceLeastInUse
+	// BalanceRandom picks uniformly at random among healthy endpoints.
+	BalanceRandom
+)
+
+// endpoint wraps a per-address sub-pool with the health/backoff state used
+// to route around a server that's failing to dial.
+type endpoint struct {
+	addr string
+	pool *channelPool
+
+	mu        sync.Mutex
+	unhealthy bool
+	backoff   time.Duration
+	retryAt   time.Time
+}
+
+func (e *endpoint) healthy() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.unhealthy {
+		return true
+	}
+	if time.Now().Before(e.retryAt) {
+		return false
+	}
+	// backoff has elapsed; let the caller try it again.
+	e.unhealthy = false
+	return true
+}
+
+func (e *endpoint) markUnhealthy() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	switch {
+	case e.backoff == 0:
+		e.backoff = 100 * time.Millisecond
+	case e.backoff < 30*time.Second:
+		e.backoff *= 2
+	}
+	e.unhealthy = true
+	e.retryAt = time.Now().Add(e.backoff)
+}
+
+// MultiEndpointPool load-balances Get/GetContext across per-endpoint
+// sub-pools, turning channelPool into a client-side load balancer. On
+// Dialer failure for one endpoint it is marked unhealthy with exponential
+// backoff and Get routes to the next healthy one; PutBack returns a
+// connection to the sub-pool it originated from.
+type MultiEndpointPool struct {
+	balance Balance
+
+	mu        sync.Mutex
+	endpoints []*endpoint
+	next      int
+
+	ownerMu sync.Mutex
+	owner   map[*rpc.Client]*endpoint
+}
+
+// NewMultiEndpointPool dials initialCap connections to each of addrs via
+// dialer and returns a pool that load-balances Get across them according to
+// balance. maxCap bounds each endpoint's sub-pool independently.
+func NewMultiEndpointPool(addrs []string, initialCap, maxCap int, dialer Dialer, balance Balance) (*MultiEndpointPool, error) {
+	if len(addrs) == 0 {
+		return nil, errors.New("rpcpool: NewMultiEndpointPool requires at least one endpoint")
+	}
+
+	m := &MultiEndpointPool{
+		balance: balance,
+		owner:   make(map[*rpc.Client]*endpoint),
+	}
+
+	for _, addr := range addrs {
+		addr := addr
+		p, err := NewChannelPool(initialCap, maxCap, func() (*rpc.Client, error) {
+			return dialer(addr)
+		})
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("rpcpool: dialing initial capacity for %s: %s", addr, err)
+		}
+		m.endpoints = append(m.endpoints, &endpoint{addr: addr, pool: p.(*channelPool)})
+	}
+
+	return m, nil
+}
+
+// pick returns the next endpoint to try according to m.balance, or nil if
+// every endpoint is currently unhealthy.
+func (m *MultiEndpointPool) pick() *endpoint {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	healthy := make([]*endpoint, 0, len(m.endpoints))
+	for _, ep := range m.endpoints {
+		if ep.healthy() {
+			healthy = append(healthy, ep)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	switch m.balance {
+	case BalanceRandom:
+		return healthy[rand.Intn(len(healthy))]
+	case BalanceLeastInUse:
+		best := healthy[0]
+		for _, ep := range healthy[1:] {
+			if ep.pool.inUse() < best.pool.inUse() {
+				best = ep
+			}
+		}
+		return best
+	default:
+		ep := healthy[m.next%len(healthy)]
+		m.next++
+		return ep
+	}
+}
+
+// Get implements the Pool interfaces Get() method.
+func (m *MultiEndpointPool) Get() (*rpc.Client, error) {
+	return m.GetContext(context.Background())
+}
+
+// GetContext tries healthy endpoints, per m.balance, until one yields a
+// connection or all of them have failed. Only dial/factory failures mark an
+// endpoint unhealthy; ctx being done is returned to the caller immediately
+// (retrying another endpoint against an already-done ctx can't help), and a
+// saturated sub-pool in ModeNonBlocking just moves on to the next endpoint.
+func (m *MultiEndpointPool) GetContext(ctx context.Context) (*rpc.Client, error) {
+	m.mu.Lock()
+	attempts := len(m.endpoints)
+	m.mu.Unlock()
+
+	for i := 0; i < attempts; i++ {
+		ep := m.pick()
+		if ep == nil {
+			return nil, ErrNoHealthyEndpoints
+		}
+
+		conn, err := ep.pool.GetContext(ctx)
+		if err == nil {
+			m.ownerMu.Lock()
+			m.owner[conn] = ep
+			m.ownerMu.Unlock()
+			return conn, nil
+		}
+
+		switch err {
+		case ErrTimeout:
+			return nil, err
+		case ErrPoolExhausted:
+			continue
+		default:
+			ep.markUnhealthy()
+			continue
+		}
+	}
+
+	return nil, ErrNoHealthyEndpoints
+}
+
+// PutBack returns conn to the sub-pool of the endpoint it was dialed from.
+func (m *MultiEndpointPool) PutBack(conn *rpc.Client) error {
+	m.ownerMu.Lock()
+	ep := m.owner[conn]
+	delete(m.owner, conn)
+	m.ownerMu.Unlock()
+
+	if ep == nil {
+		return errors.New("rpcpool: connection did not originate from this pool")
+	}
+	return ep.pool.PutBack(conn)
+}
+
+// PutBackWithError returns conn to the sub-pool it was dialed from, marking
+// it unhealthy if rpcErr is non-nil so it is closed rather than reused.
+func (m *MultiEndpointPool) PutBackWithError(conn *rpc.Client, rpcErr error) error {
+	m.ownerMu.Lock()
+	ep := m.owner[conn]
+	delete(m.owner, conn)
+	m.ownerMu.Unlock()
+
+	if ep == nil {
+		return errors.New("rpcpool: connection did not originate from this pool")
+	}
+	return ep.pool.PutBackWithError(conn, rpcErr)
+}
+
+// Close closes every endpoint's sub-pool. The pool is no longer usable
+// afterwards.
+func (m *MultiEndpointPool) Close() {
+	m.mu.Lock()
+	endpoints := m.endpoints
+	m.endpoints = nil
+	m.mu.Unlock()
+
+	for _, ep := range endpoints {
+		ep.pool.Close()
+	}
+}
+
+// Len returns the total number of idle connections across all endpoints.
+func (m *MultiEndpointPool) Len() int {
+	m.mu.Lock()
+	endpoints := m.endpoints
+	m.mu.Unlock()
+
+	n := 0
+	for _, ep := range endpoints {
+		n += ep.pool.Len()
+	}
+	return n
+}
+
+// Stats returns the sum of every endpoint's PoolStats.
+func (m *MultiEndpointPool) Stats() PoolStats {
+	m.mu.Lock()
+	endpoints := m.endpoints
+	m.mu.Unlock()
+
+	var total PoolStats
+	for _, ep := range endpoints {
+		s := ep.pool.Stats()
+		total.Idle += s.Idle
+		total.InUse += s.InUse
+		total.TotalCreated += s.TotalCreated
+		total.TotalClosed += s.TotalClosed
+		total.WaitCount += s.WaitCount
+		total.WaitDuration += s.WaitDuration
+		for i, n := range s.WaitHistogram {
+			total.WaitHistogram[i] += n
+		}
+	}
+	return total
+}
+
+// SetMaxCap applies n to every endpoint's sub-pool, stopping at the first
+// one that refuses to resize.
+func (m *MultiEndpointPool) SetMaxCap(n int) error {
+	m.mu.Lock()
+	endpoints := m.endpoints
+	m.mu.Unlock()
+
+	for _, ep := range endpoints {
+		if err := ep.pool.SetMaxCap(n); err != nil {
+			return fmt.Errorf("rpcpool: resizing endpoint %s: %w", ep.addr, err)
+		}
+	}
+	return nil
+}
+
+// SetMinCap applies n to every endpoint's sub-pool, stopping at the first
+// one whose Factory fails to grow it.
+func (m *MultiEndpointPool) SetMinCap(n int) error {
+	m.mu.Lock()
+	endpoints := m.endpoints
+	m.mu.Unlock()
+
+	for _, ep := range endpoints {
+		if err := ep.pool.SetMinCap(n); err != nil {
+			return fmt.Errorf("rpcpool: growing endpoint %s: %w", ep.addr, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package rpcpool
+
+import (
+	"context"
+	"errors"
+	"net/rpc"
+	"testing"
+)
+
+func TestMultiEndpointPoolFailsOverOnDialError(t *testing.T) {
+	goodFactory := newTestClientFactory(t)
+	badAttempts := 0
+	dialer := func(addr string) (*rpc.Client, error) {
+		if addr == "bad" {
+			badAttempts++
+			return nil, errors.New("dial refused")
+		}
+		return goodFactory()
+	}
+
+	m, err := NewMultiEndpointPool([]string{"bad", "good"}, 0, 1, dialer, BalanceRoundRobin)
+	if err != nil {
+		t.Fatalf("NewMultiEndpointPool: %v", err)
+	}
+	defer m.Close()
+
+	conn, err := m.Get()
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if badAttempts == 0 {
+		t.Fatalf("expected the dialer to be tried against the bad endpoint before failing over")
+	}
+	if err := m.PutBack(conn); err != nil {
+		t.Fatalf("PutBack: %v", err)
+	}
+}
+
+func TestMultiEndpointPoolPassesThroughCtxTimeout(t *testing.T) {
+	factory := newTestClientFactory(t)
+	dialer := func(addr string) (*rpc.Client, error) { return factory() }
+
+	m, err := NewMultiEndpointPool([]string{"only"}, 0, 1, dialer, BalanceRoundRobin)
+	if err != nil {
+		t.Fatalf("NewMultiEndpointPool: %v", err)
+	}
+	defer m.Close()
+
+	conn, err := m.Get() // fills the endpoint's only slot
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := m.GetContext(ctx); err != ErrTimeout {
+		t.Fatalf("expected ErrTimeout to pass through, got %v", err)
+	}
+
+	if err := m.PutBack(conn); err != nil {
+		t.Fatalf("PutBack: %v", err)
+	}
+	if _, err := m.Get(); err != nil {
+		t.Fatalf("expected endpoint to remain healthy after a canceled ctx, got %v", err)
+	}
+}
@@ -0,0 +1,299 @@
+package rpcpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/rpc"
+	"time"
+)
+
+// connMeta tracks the lifecycle timestamps for a pooled *rpc.Client, mirroring
+// the bookkeeping grpc-pool style designs keep alongside each connection.
+type connMeta struct {
+	timeInitiated time.Time
+	timeUsed      time.Time
+	unhealthy     bool
+}
+
+// Mode selects how Get/GetContext behaves once MaxCap connections are
+// already in use.
+type Mode int
+
+const (
+	// ModeBlocking waits until a connection is returned via PutBack or ctx
+	// is done, returning ErrTimeout in the latter case. It is the zero value
+	// and the mode used by NewChannelPool, so MaxCap is a hard cap callers
+	// wait on rather than a soft one the pool creates past.
+	ModeBlocking Mode = iota
+
+	// ModeNonBlocking fails fast with ErrPoolExhausted instead of waiting
+	// for a connection to free up.
+	ModeNonBlocking
+)
+
+// Options configures optional eviction, health-check, and blocking behavior
+// for NewChannelPoolWithOptions.
+type Options struct {
+	// IdleTimeout, if non-zero, is the maximum duration a connection may sit
+	// idle in the pool before Get discards it and dials a replacement.
+	IdleTimeout time.Duration
+
+	// MaxLifetime, if non-zero, is the maximum duration a connection may be
+	// in service, from creation, before PutBack closes it instead of
+	// returning it to the pool.
+	MaxLifetime time.Duration
+
+	// MinCap, if non-zero, is the minimum number of idle connections the
+	// reaper leaves in place; it stops evicting idle-expired connections once
+	// the idle count has dropped to MinCap.
+	MinCap int
+
+	// Ping, if set, validates a connection pulled from the idle pool before
+	// Get hands it to a caller, replacing it via the Factory on failure.
+	Ping Ping
+
+	// HealthCheckInterval, if non-zero, runs Ping against every idle
+	// connection on this interval in the background, closing and forgetting
+	// any that fail rather than waiting for a caller to discover it via Get.
+	// It has no effect unless Ping is also set.
+	HealthCheckInterval time.Duration
+
+	// Mode controls whether Get/GetContext block once MaxCap connections are
+	// in use (ModeBlocking, the default) or fail fast with ErrPoolExhausted
+	// (ModeNonBlocking).
+	Mode Mode
+
+	// Hooks registers optional lifecycle callbacks for instrumentation.
+	Hooks Hooks
+}
+
+// NewChannelPoolWithOptions is like NewChannelPool but additionally accepts
+// Options controlling idle and lifetime-based eviction, and an optional
+// health check, for pooled connections. When IdleTimeout is non-zero a
+// background reaper periodically evicts connections that have sat idle too
+// long. Options are wired in before the pool is filled to initialCap, so
+// Hooks.OnCreate fires for the initial fill the same as it does for any
+// later factory dial.
+func NewChannelPoolWithOptions(initialCap, maxCap int, factory Factory, opts Options) (Pool, error) {
+	if initialCap < 0 || maxCap <= 0 || initialCap > maxCap {
+		return nil, errors.New("invalid capacity settings")
+	}
+
+	p, err := NewChannelPool(0, maxCap, factory)
+	if err != nil {
+		return nil, err
+	}
+
+	c := p.(*channelPool)
+	c.idleTimeout = opts.IdleTimeout
+	c.maxLifetime = opts.MaxLifetime
+	c.minCap = opts.MinCap
+	c.ping = opts.Ping
+	c.mode = opts.Mode
+	c.hooks = opts.Hooks
+	c.healthCheckInterval = opts.HealthCheckInterval
+
+	for i := 0; i < initialCap; i++ {
+		conn, err := c.factory(context.Background())
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("factory is not able to fill the pool: %s", err)
+		}
+		c.recordNew(conn)
+		c.conns <- conn
+	}
+
+	if c.idleTimeout > 0 {
+		go c.reapLoop()
+	}
+	if c.healthCheckInterval > 0 && c.ping != nil {
+		go c.healthCheckLoop()
+	}
+
+	return c, nil
+}
+
+// recordNew registers meta for a freshly dialed connection, updates
+// TotalCreated, and fires OnCreate.
+func (c *channelPool) recordNew(conn *rpc.Client) {
+	now := time.Now()
+	c.metaMu.Lock()
+	if c.meta == nil {
+		c.meta = make(map[*rpc.Client]*connMeta)
+	}
+	c.meta[conn] = &connMeta{timeInitiated: now, timeUsed: now}
+	c.metaMu.Unlock()
+
+	c.stats.recordCreate()
+	c.runHook(c.hooks.OnCreate, conn)
+}
+
+// touch updates the idle timestamp for conn, called when it's returned to
+// the pool via PutBack.
+func (c *channelPool) touch(conn *rpc.Client) {
+	c.metaMu.Lock()
+	if m := c.meta[conn]; m != nil {
+		m.timeUsed = time.Now()
+	}
+	c.metaMu.Unlock()
+}
+
+// markUnhealthy flags conn as unhealthy, after a failed Ping check or an RPC
+// error reported via PutBackWithError; PutBack closes flagged connections
+// instead of returning them to the pool.
+func (c *channelPool) markUnhealthy(conn *rpc.Client) {
+	c.metaMu.Lock()
+	if m := c.meta[conn]; m != nil {
+		m.unhealthy = true
+	}
+	c.metaMu.Unlock()
+}
+
+// isUnhealthy reports whether conn has been flagged unhealthy, by a failed
+// Ping or by the caller via PutBackWithError.
+func (c *channelPool) isUnhealthy(conn *rpc.Client) bool {
+	c.metaMu.Lock()
+	m := c.meta[conn]
+	c.metaMu.Unlock()
+	return m != nil && m.unhealthy
+}
+
+// forget drops the tracked meta for conn, called once it's closed for good.
+func (c *channelPool) forget(conn *rpc.Client) {
+	c.metaMu.Lock()
+	delete(c.meta, conn)
+	c.metaMu.Unlock()
+}
+
+// idleExpired reports whether conn has been idle in the pool longer than
+// idleTimeout.
+func (c *channelPool) idleExpired(conn *rpc.Client) bool {
+	if c.idleTimeout <= 0 {
+		return false
+	}
+	c.metaMu.Lock()
+	m := c.meta[conn]
+	c.metaMu.Unlock()
+	return m != nil && time.Since(m.timeUsed) > c.idleTimeout
+}
+
+// lifetimeExpired reports whether conn has been alive longer than
+// maxLifetime.
+func (c *channelPool) lifetimeExpired(conn *rpc.Client) bool {
+	if c.maxLifetime <= 0 {
+		return false
+	}
+	c.metaMu.Lock()
+	m := c.meta[conn]
+	c.metaMu.Unlock()
+	return m != nil && time.Since(m.timeInitiated) > c.maxLifetime
+}
+
+// reapLoop periodically evicts idle connections that have exceeded
+// idleTimeout until the pool is closed.
+func (c *channelPool) reapLoop() {
+	ticker := time.NewTicker(c.idleTimeout)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.reapIdle()
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// reapIdle drains the idle conns channel once, closing any connection past
+// idleTimeout and returning the rest. It stops evicting once the idle count
+// has dropped to minCap, preserving at least that many warm connections.
+func (c *channelPool) reapIdle() {
+	// Hold closeMu for the duration of the drain so Close() can't close
+	// conns out from under the re-send below.
+	c.closeMu.RLock()
+	defer c.closeMu.RUnlock()
+
+	conns, _ := c.getConnsAndFactory()
+	if conns == nil {
+		return
+	}
+
+	remaining := len(conns)
+	for i, n := 0, len(conns); i < n; i++ {
+		select {
+		case conn := <-conns:
+			if conn == nil {
+				remaining--
+				continue
+			}
+			if remaining > c.minCap && c.idleExpired(conn) {
+				c.forget(conn)
+				c.closeConn(conn)
+				remaining--
+				continue
+			}
+			select {
+			case conns <- conn:
+			default:
+				c.forget(conn)
+				c.closeConn(conn)
+				remaining--
+			}
+		default:
+			return
+		}
+	}
+}
+
+// healthCheckLoop periodically pings every idle connection until the pool
+// is closed.
+func (c *channelPool) healthCheckLoop() {
+	ticker := time.NewTicker(c.healthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.healthCheckIdle()
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// healthCheckIdle drains the idle conns channel once, pinging each
+// connection and closing any that fails rather than waiting for a caller to
+// discover it via Get.
+func (c *channelPool) healthCheckIdle() {
+	// Hold closeMu for the duration of the drain so Close() can't close
+	// conns out from under the re-send below.
+	c.closeMu.RLock()
+	defer c.closeMu.RUnlock()
+
+	conns, _ := c.getConnsAndFactory()
+	if conns == nil {
+		return
+	}
+
+	for i, n := 0, len(conns); i < n; i++ {
+		select {
+		case conn := <-conns:
+			if conn == nil {
+				continue
+			}
+			if !c.checkPing(conn) {
+				c.forget(conn)
+				c.closeConn(conn)
+				continue
+			}
+			select {
+			case conns <- conn:
+			default:
+				c.forget(conn)
+				c.closeConn(conn)
+			}
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package rpcpool
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReapIdleEvictsExpiredConnections(t *testing.T) {
+	p, err := NewChannelPoolWithOptions(2, 2, newTestClientFactory(t), Options{
+		IdleTimeout: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewChannelPoolWithOptions: %v", err)
+	}
+	defer p.Close()
+
+	time.Sleep(5 * time.Millisecond)
+	p.(*channelPool).reapIdle()
+
+	if got := p.Len(); got != 0 {
+		t.Fatalf("expected all idle-expired connections to be reaped, got Len()=%d", got)
+	}
+}
+
+func TestReapIdleRespectsMinCap(t *testing.T) {
+	p, err := NewChannelPoolWithOptions(3, 3, newTestClientFactory(t), Options{
+		IdleTimeout: time.Millisecond,
+		MinCap:      1,
+	})
+	if err != nil {
+		t.Fatalf("NewChannelPoolWithOptions: %v", err)
+	}
+	defer p.Close()
+
+	time.Sleep(5 * time.Millisecond)
+	p.(*channelPool).reapIdle()
+
+	if got := p.Len(); got != 1 {
+		t.Fatalf("expected reapIdle to stop at MinCap=1, got Len()=%d", got)
+	}
+}
+
+func TestCloseDoesNotRaceReapIdle(t *testing.T) {
+	p, err := NewChannelPoolWithOptions(4, 4, newTestClientFactory(t), Options{
+		IdleTimeout: time.Nanosecond,
+	})
+	if err != nil {
+		t.Fatalf("NewChannelPoolWithOptions: %v", err)
+	}
+	c := p.(*channelPool)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			c.reapIdle()
+		}
+	}()
+
+	p.Close()
+	wg.Wait()
+}
+
+func TestMaxLifetimeClosesOnPutBack(t *testing.T) {
+	p, err := NewChannelPoolWithOptions(0, 1, newTestClientFactory(t), Options{
+		MaxLifetime: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewChannelPoolWithOptions: %v", err)
+	}
+	defer p.Close()
+
+	conn, err := p.Get()
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if err := p.PutBack(conn); err != nil {
+		t.Fatalf("PutBack: %v", err)
+	}
+
+	if got := p.Len(); got != 0 {
+		t.Fatalf("expected lifetime-expired connection to be closed, not pooled, got Len()=%d", got)
+	}
+}
+
+func TestPutBackWithErrorClosesConnection(t *testing.T) {
+	p, err := NewChannelPool(0, 1, newTestClientFactory(t))
+	if err != nil {
+		t.Fatalf("NewChannelPool: %v", err)
+	}
+	defer p.Close()
+
+	conn, err := p.Get()
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if err := p.PutBackWithError(conn, errors.New("rpc failed")); err != nil {
+		t.Fatalf("PutBackWithError: %v", err)
+	}
+
+	if got := p.Len(); got != 0 {
+		t.Fatalf("expected connection flagged unhealthy to be closed, not pooled, got Len()=%d", got)
+	}
+}
@@ -0,0 +1,83 @@
+package rpcpool
+
+import (
+	"net/rpc"
+	"sync"
+	"testing"
+	"time"
+)
+
+func echoPing(conn *rpc.Client) error {
+	var reply int
+	return conn.Call("Echo.Echo", new(int), &reply)
+}
+
+func TestPingReplacesFailedConnectionOnGet(t *testing.T) {
+	p, err := NewChannelPoolWithOptions(1, 2, newTestClientFactory(t), Options{Ping: echoPing})
+	if err != nil {
+		t.Fatalf("NewChannelPoolWithOptions: %v", err)
+	}
+	defer p.Close()
+
+	conn, err := p.Get()
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	conn.Close() // simulate the underlying connection having gone bad
+	if err := p.PutBack(conn); err != nil {
+		t.Fatalf("PutBack: %v", err)
+	}
+
+	replacement, err := p.Get()
+	if err != nil {
+		t.Fatalf("Get after PutBack: %v", err)
+	}
+	if replacement == conn {
+		t.Fatalf("expected Get to discard the failed connection and dial a replacement")
+	}
+}
+
+func TestHealthCheckIdleEvictsFailedConnections(t *testing.T) {
+	p, err := NewChannelPoolWithOptions(1, 1, newTestClientFactory(t), Options{
+		Ping:                echoPing,
+		HealthCheckInterval: time.Hour, // large so the ticker itself never fires during the test
+	})
+	if err != nil {
+		t.Fatalf("NewChannelPoolWithOptions: %v", err)
+	}
+	defer p.Close()
+
+	c := p.(*channelPool)
+	conn := <-c.conns
+	conn.Close() // simulate the idle connection having gone bad
+	c.conns <- conn
+
+	c.healthCheckIdle()
+
+	if got := p.Len(); got != 0 {
+		t.Fatalf("expected the failed idle connection to be evicted, got Len()=%d", got)
+	}
+}
+
+func TestCloseDoesNotRaceHealthCheckIdle(t *testing.T) {
+	p, err := NewChannelPoolWithOptions(4, 4, newTestClientFactory(t), Options{
+		Ping:                echoPing,
+		HealthCheckInterval: time.Hour, // large so the ticker itself never fires during the test
+	})
+	if err != nil {
+		t.Fatalf("NewChannelPoolWithOptions: %v", err)
+	}
+	c := p.(*channelPool)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			c.healthCheckIdle()
+		}
+	}()
+
+	p.Close()
+	wg.Wait()
+}
@@ -0,0 +1,65 @@
+package rpcpool
+
+import (
+	"context"
+	"errors"
+	"net/rpc"
+)
+
+// ErrClosed is returned by Pool methods when the pool has already been
+// closed via Close().
+var ErrClosed = errors.New("rpcpool: pool is closed")
+
+// ErrTimeout is returned by GetContext when ctx is canceled or its deadline
+// is exceeded before a connection becomes available.
+var ErrTimeout = errors.New("rpcpool: timed out waiting for a connection")
+
+// ErrPoolExhausted is returned by GetContext in ModeNonBlocking when the
+// pool has reached MaxCap in-use connections and none are immediately
+// available.
+var ErrPoolExhausted = errors.New("rpcpool: pool exhausted at max capacity")
+
+// Pool is the interface implemented by channelPool and MultiEndpointPool. It
+// describes a pool of long-lived *rpc.Client connections that can be handed
+// out to callers and returned for reuse.
+type Pool interface {
+	// Get returns a connection from the pool, creating one via the Factory
+	// if none is idle. If the pool has reached MaxCap in-use connections, it
+	// blocks until one is returned via PutBack (ModeBlocking) or fails with
+	// ErrPoolExhausted (ModeNonBlocking).
+	Get() (*rpc.Client, error)
+
+	// GetContext is like Get but honors ctx cancellation and deadlines while
+	// waiting for a connection to become available, returning ErrTimeout if
+	// ctx is done first.
+	GetContext(ctx context.Context) (*rpc.Client, error)
+
+	// PutBack returns conn to the pool. If the pool is full or closed, conn
+	// is simply closed.
+	PutBack(conn *rpc.Client) error
+
+	// PutBackWithError is like PutBack, but lets the caller report an RPC
+	// error encountered using conn. A non-nil rpcErr marks conn unhealthy so
+	// it is closed rather than reused.
+	PutBackWithError(conn *rpc.Client, rpcErr error) error
+
+	// Close closes the pool and all connections it holds. The pool is no
+	// longer usable afterwards.
+	Close()
+
+	// Len returns the number of idle connections currently in the pool.
+	Len() int
+
+	// Stats returns a snapshot of the pool's connection counts and Get wait
+	// latency, suitable for wiring into Prometheus or similar.
+	Stats() PoolStats
+
+	// SetMaxCap resizes the pool's maximum capacity. It fails if the pool
+	// currently has connections checked out, since live resizing is only
+	// safe while the pool is idle.
+	SetMaxCap(n int) error
+
+	// SetMinCap grows the idle pool up to n connections via the Factory,
+	// failing if the Factory errors partway through.
+	SetMinCap(n int) error
+}
@@ -0,0 +1,127 @@
+package rpcpool
+
+import (
+	"net/rpc"
+	"sync"
+	"time"
+)
+
+// waitBucketBounds are the upper bounds, exclusive, of the buckets in
+// PoolStats.WaitHistogram: <1ms, <10ms, <100ms, <1s, and >=1s.
+var waitBucketBounds = [4]time.Duration{
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+}
+
+// PoolStats is a snapshot of a Pool's connection counts and Get wait
+// latency, mirroring the kind of counters database/sql exposes via
+// DB.Stats().
+type PoolStats struct {
+	// Idle is the number of connections currently idle in the pool.
+	Idle int
+	// InUse is the number of connections currently checked out via Get.
+	InUse int
+	// TotalCreated is the number of connections ever dialed via the Factory.
+	TotalCreated int64
+	// TotalClosed is the number of connections ever closed by the pool,
+	// whether idle-evicted, lifetime-evicted, failed a Ping, or discarded on
+	// Close.
+	TotalClosed int64
+	// WaitCount is the number of Get/GetContext calls that obtained a sem
+	// slot, whether or not they had to wait for it. Calls that fail with
+	// ErrTimeout or ErrPoolExhausted are not counted.
+	WaitCount int64
+	// WaitDuration is the cumulative time those calls spent waiting for a
+	// sem slot.
+	WaitDuration time.Duration
+	// WaitHistogram buckets WaitCount by how long each call waited:
+	// [0]<1ms, [1]<10ms, [2]<100ms, [3]<1s, [4]>=1s.
+	WaitHistogram [5]int64
+}
+
+// ConnHook is a lifecycle callback invoked with the connection it concerns.
+// Hooks are called synchronously; a slow hook delays the caller.
+type ConnHook func(conn *rpc.Client)
+
+// Hooks are optional lifecycle callbacks a channelPool invokes around
+// connection creation, closing, and checkout, for wiring into custom
+// instrumentation beyond Stats().
+type Hooks struct {
+	// OnCreate is called after a new connection is dialed via the Factory.
+	OnCreate ConnHook
+	// OnClose is called just before the pool closes a connection for good.
+	OnClose ConnHook
+	// OnGet is called just before Get/GetContext returns conn to the caller.
+	OnGet ConnHook
+	// OnPutBack is called when a caller returns conn via PutBack, before the
+	// pool decides whether to reuse or close it.
+	OnPutBack ConnHook
+}
+
+// stats holds the live counters backing Stats(); guarded by its own mutex so
+// readers don't contend with conns/workConns traffic.
+type stats struct {
+	mu            sync.Mutex
+	totalCreated  int64
+	totalClosed   int64
+	waitCount     int64
+	waitDuration  time.Duration
+	waitHistogram [5]int64
+}
+
+func (s *stats) recordCreate() {
+	s.mu.Lock()
+	s.totalCreated++
+	s.mu.Unlock()
+}
+
+func (s *stats) recordClose() {
+	s.mu.Lock()
+	s.totalClosed++
+	s.mu.Unlock()
+}
+
+func (s *stats) recordWait(d time.Duration) {
+	bucket := len(waitBucketBounds)
+	for i, bound := range waitBucketBounds {
+		if d < bound {
+			bucket = i
+			break
+		}
+	}
+
+	s.mu.Lock()
+	s.waitCount++
+	s.waitDuration += d
+	s.waitHistogram[bucket]++
+	s.mu.Unlock()
+}
+
+func (s *stats) snapshot() (totalCreated, totalClosed, waitCount int64, waitDuration time.Duration, waitHistogram [5]int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.totalCreated, s.totalClosed, s.waitCount, s.waitDuration, s.waitHistogram
+}
+
+// Stats implements the Pool interfaces Stats() method.
+func (c *channelPool) Stats() PoolStats {
+	totalCreated, totalClosed, waitCount, waitDuration, waitHistogram := c.stats.snapshot()
+	return PoolStats{
+		Idle:          c.Len(),
+		InUse:         c.inUse(),
+		TotalCreated:  totalCreated,
+		TotalClosed:   totalClosed,
+		WaitCount:     waitCount,
+		WaitDuration:  waitDuration,
+		WaitHistogram: waitHistogram,
+	}
+}
+
+// runHook invokes hook with conn if the pool has one configured.
+func (c *channelPool) runHook(hook ConnHook, conn *rpc.Client) {
+	if hook != nil {
+		hook(conn)
+	}
+}
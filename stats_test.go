@@ -0,0 +1,96 @@
+package rpcpool
+
+import (
+	"net/rpc"
+	"sync/atomic"
+	"testing"
+)
+
+func TestStatsTracksCreatedAndClosed(t *testing.T) {
+	p, err := NewChannelPool(2, 2, newTestClientFactory(t))
+	if err != nil {
+		t.Fatalf("NewChannelPool: %v", err)
+	}
+
+	if got := p.Stats().TotalCreated; got != 2 {
+		t.Fatalf("expected TotalCreated=2 after initial fill, got %d", got)
+	}
+
+	conn, err := p.Get()
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if err := p.PutBack(conn); err != nil {
+		t.Fatalf("PutBack: %v", err)
+	}
+
+	p.Close()
+	if got := p.Stats().TotalClosed; got != 2 {
+		t.Fatalf("expected TotalClosed=2 after Close, got %d", got)
+	}
+}
+
+func TestHooksFireOnCreateGetPutBackClose(t *testing.T) {
+	var created, got, putBack, closed int32
+	hooks := Hooks{
+		OnCreate:  func(*rpc.Client) { atomic.AddInt32(&created, 1) },
+		OnGet:     func(*rpc.Client) { atomic.AddInt32(&got, 1) },
+		OnPutBack: func(*rpc.Client) { atomic.AddInt32(&putBack, 1) },
+		OnClose:   func(*rpc.Client) { atomic.AddInt32(&closed, 1) },
+	}
+
+	p, err := NewChannelPoolWithOptions(0, 1, newTestClientFactory(t), Options{Hooks: hooks})
+	if err != nil {
+		t.Fatalf("NewChannelPoolWithOptions: %v", err)
+	}
+
+	conn, err := p.Get()
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if err := p.PutBack(conn); err != nil {
+		t.Fatalf("PutBack: %v", err)
+	}
+	p.Close()
+
+	if created != 1 || got != 1 || putBack != 1 || closed != 1 {
+		t.Fatalf("expected each hook to fire once, got created=%d get=%d putBack=%d closed=%d",
+			created, got, putBack, closed)
+	}
+}
+
+func TestHooksFireOnCreateForInitialFill(t *testing.T) {
+	var created int32
+	hooks := Hooks{
+		OnCreate: func(*rpc.Client) { atomic.AddInt32(&created, 1) },
+	}
+
+	p, err := NewChannelPoolWithOptions(3, 3, newTestClientFactory(t), Options{Hooks: hooks})
+	if err != nil {
+		t.Fatalf("NewChannelPoolWithOptions: %v", err)
+	}
+	defer p.Close()
+
+	if created != 3 {
+		t.Fatalf("expected OnCreate to fire for each initial-fill connection, got %d", created)
+	}
+}
+
+func TestSetMaxCapAndSetMinCap(t *testing.T) {
+	p, err := NewChannelPool(1, 2, newTestClientFactory(t))
+	if err != nil {
+		t.Fatalf("NewChannelPool: %v", err)
+	}
+	defer p.Close()
+
+	if err := p.SetMaxCap(4); err != nil {
+		t.Fatalf("SetMaxCap: %v", err)
+	}
+	if err := p.SetMinCap(3); err != nil {
+		t.Fatalf("SetMinCap: %v", err)
+	}
+
+	if got := p.Len(); got != 3 {
+		t.Fatalf("expected 3 idle connections after SetMinCap(3), got %d", got)
+	}
+}